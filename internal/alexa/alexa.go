@@ -0,0 +1,260 @@
+// Package alexa provides a minimal client for the bits of Amazon's Login
+// with Amazon (LWA) and Alexa Skill Management API (SMAPI) that this step
+// needs: exchanging a refresh token for an access token, and fetching skill
+// metrics.
+package alexa
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AuthResponse is the response body returned by the LWA token endpoint.
+type AuthResponse struct {
+	AccessToken  string `json:"access_token"`
+	ExpiresIn    int    `json:"expires_in"`
+	TokenType    string `json:"token_type"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// MetricsResponse is the response body returned by the SMAPI skill metrics
+// endpoint for a single metric. Locale and Stage aren't part of the SMAPI
+// response body; they're copied from the MetricRequest that produced this
+// result so callers can tell series for the same metric apart when a
+// request fans out across locales and stages.
+type MetricsResponse struct {
+	Metric     string    `json:"metric"`
+	Timestamps []string  `json:"timestamps"`
+	Values     []float64 `json:"values"`
+
+	Locale string `json:"locale,omitempty"`
+	Stage  string `json:"stage,omitempty"`
+}
+
+// MetricRequest describes a single SMAPI skill metrics call.
+type MetricRequest struct {
+	SkillID   string
+	Metric    string
+	Locale    string
+	Stage     string
+	SkillType string
+	Period    string
+	StartTime time.Time
+	EndTime   time.Time
+}
+
+const (
+	defaultLWAOAuthURL  = "https://api.amazon.com/auth/o2/token"
+	defaultSMAPIBaseURL = "https://api.amazonalexa.com"
+)
+
+// Client talks to LWA and SMAPI on behalf of a single skill.
+type Client struct {
+	HTTPClient *http.Client
+
+	LWAClientID     string
+	LWAClientSecret string
+	LWARefreshToken string
+
+	// lwaOAuthURL and smapiBaseURL are overridden in tests to point at an
+	// httptest server instead of the real Amazon endpoints.
+	lwaOAuthURL  string
+	smapiBaseURL string
+
+	tokenMu     sync.Mutex
+	accessToken string
+}
+
+// NewClient returns a Client ready to make requests, using a sane default
+// HTTP timeout.
+func NewClient(lwaClientID, lwaClientSecret, lwaRefreshToken string) *Client {
+	return &Client{
+		HTTPClient:      &http.Client{Timeout: 200 * time.Second},
+		LWAClientID:     lwaClientID,
+		LWAClientSecret: lwaClientSecret,
+		LWARefreshToken: lwaRefreshToken,
+		lwaOAuthURL:     defaultLWAOAuthURL,
+		smapiBaseURL:    defaultSMAPIBaseURL,
+	}
+}
+
+// SetAccessToken primes the client with an already-obtained access token,
+// skipping the initial GetAccessToken call. FetchMetric still refreshes it
+// automatically if SMAPI reports it's expired.
+func (c *Client) SetAccessToken(accessToken string) {
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+	c.accessToken = accessToken
+}
+
+func (c *Client) currentAccessToken() string {
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+	return c.accessToken
+}
+
+func metricsSMAPIURL(baseURL string, req MetricRequest) string {
+	query := url.Values{}
+	query.Set("startTime", formatTimeDate(req.StartTime))
+	query.Set("endTime", formatTimeDate(req.EndTime))
+	query.Set("period", req.Period)
+	query.Set("metric", req.Metric)
+	query.Set("stage", req.Stage)
+	query.Set("skillType", req.SkillType)
+	query.Set("locale", req.Locale)
+
+	return baseURL + "/v1/skills/" + req.SkillID + "/metrics?" + query.Encode()
+}
+
+func formatTimeDate(t time.Time) string {
+	return fmt.Sprintf("%d-%02d-%02dT%02d:%02d:%02dZ", t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second())
+}
+
+// GetAccessToken exchanges the configured refresh token for a fresh LWA
+// access token and caches it on the client for subsequent FetchMetric calls.
+func (c *Client) GetAccessToken() (AuthResponse, error) {
+	var auth AuthResponse
+
+	bodyString := "grant_type=refresh_token"
+	bodyString += "&client_id=" + c.LWAClientID
+	bodyString += "&client_secret=" + c.LWAClientSecret
+	bodyString += "&refresh_token=" + c.LWARefreshToken
+
+	req, err := http.NewRequest("POST", c.lwaOAuthURL, strings.NewReader(bodyString))
+	if err != nil {
+		return auth, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return auth, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return auth, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return auth, &AuthError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	if err := json.Unmarshal(body, &auth); err != nil {
+		return auth, err
+	}
+
+	c.SetAccessToken(auth.AccessToken)
+	return auth, nil
+}
+
+// FetchMetric fetches the single metric series described by req. It retries
+// transient failures (5xx, network errors, 429 with backoff honoring
+// Retry-After), and if SMAPI reports the access token has expired (401), it
+// refreshes the token once via GetAccessToken and retries the request.
+func (c *Client) FetchMetric(ctx context.Context, req MetricRequest) (MetricsResponse, error) {
+	var metricResponse MetricsResponse
+
+	resp, err := c.fetchMetricOnce(ctx, req)
+	if err != nil {
+		return metricResponse, err
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		resp.Body.Close()
+		if _, authErr := c.GetAccessToken(); authErr != nil {
+			return metricResponse, authErr
+		}
+
+		resp, err = c.fetchMetricOnce(ctx, req)
+		if err != nil {
+			return metricResponse, err
+		}
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return metricResponse, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		smapiErr := &SMAPIError{StatusCode: resp.StatusCode}
+		_ = json.Unmarshal(body, smapiErr)
+		return metricResponse, smapiErr
+	}
+
+	if err := json.Unmarshal(body, &metricResponse); err != nil {
+		return metricResponse, err
+	}
+
+	metricResponse.Locale = req.Locale
+	metricResponse.Stage = req.Stage
+
+	return metricResponse, nil
+}
+
+func (c *Client) fetchMetricOnce(ctx context.Context, req MetricRequest) (*http.Response, error) {
+	url := metricsSMAPIURL(c.smapiBaseURL, req)
+
+	return c.doWithRetry(ctx, func() (*http.Request, error) {
+		httpReq, err := http.NewRequestWithContext(ctx, "GET", url, strings.NewReader(""))
+		if err != nil {
+			return nil, err
+		}
+		httpReq.Header.Set("Authorization", "Bearer "+c.currentAccessToken())
+		return httpReq, nil
+	})
+}
+
+// FetchMetrics fetches every request in reqs concurrently, bounded to at
+// most maxConcurrent requests in flight at once, and returns one
+// MetricsResponse and one error per request, both in the same order as
+// reqs (errs[i] is nil when reqs[i] succeeded). If maxConcurrent is <= 0,
+// it defaults to 1 (sequential).
+//
+// Unlike an errgroup, a failed request does not cancel the others: this
+// lets a single flaky metric fail without losing every other metric in the
+// run. Cancel ctx to stop early anyway.
+func (c *Client) FetchMetrics(ctx context.Context, reqs []MetricRequest, maxConcurrent int) ([]MetricsResponse, []error) {
+	if maxConcurrent <= 0 {
+		maxConcurrent = 1
+	}
+
+	results := make([]MetricsResponse, len(reqs))
+	errs := make([]error, len(reqs))
+
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, maxConcurrent)
+
+	for i, req := range reqs {
+		i, req := i, req
+
+		select {
+		case <-ctx.Done():
+			errs[i] = ctx.Err()
+			continue
+		default:
+		}
+
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			results[i], errs[i] = c.FetchMetric(ctx, req)
+		}()
+	}
+
+	wg.Wait()
+
+	return results, errs
+}
@@ -0,0 +1,244 @@
+package alexa
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func testRequest() MetricRequest {
+	return MetricRequest{
+		SkillID:   "skill1",
+		Metric:    "totalEnablements",
+		Locale:    "en-US",
+		Stage:     "live",
+		SkillType: "custom",
+		Period:    "P1D",
+		StartTime: time.Now().AddDate(0, 0, -7),
+		EndTime:   time.Now(),
+	}
+}
+
+func TestGetAccessToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"access_token":"token123","expires_in":3600,"token_type":"bearer","refresh_token":"refresh123"}`))
+	}))
+	defer server.Close()
+
+	client := NewClient("id", "secret", "refresh")
+	client.HTTPClient = server.Client()
+	client.lwaOAuthURL = server.URL
+
+	auth, err := client.GetAccessToken()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if auth.AccessToken != "token123" {
+		t.Errorf("expected access token %q, got %q", "token123", auth.AccessToken)
+	}
+}
+
+func TestGetAccessTokenError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"invalid_grant"}`))
+	}))
+	defer server.Close()
+
+	client := NewClient("id", "secret", "refresh")
+	client.HTTPClient = server.Client()
+	client.lwaOAuthURL = server.URL
+
+	_, err := client.GetAccessToken()
+	var authErr *AuthError
+	if !errors.As(err, &authErr) {
+		t.Fatalf("expected *AuthError, got %T: %v", err, err)
+	}
+	if authErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d", http.StatusBadRequest, authErr.StatusCode)
+	}
+}
+
+func TestFetchMetric(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer token123" {
+			t.Errorf("expected Authorization header %q, got %q", "Bearer token123", got)
+		}
+		if got := r.URL.Query().Get("locale"); got != "en-US" {
+			t.Errorf("expected locale %q, got %q", "en-US", got)
+		}
+		w.Write([]byte(`{"metric":"totalEnablements","timestamps":["2020-01-01T00:00:00Z"],"values":[1]}`))
+	}))
+	defer server.Close()
+
+	client := NewClient("id", "secret", "refresh")
+	client.HTTPClient = server.Client()
+	client.smapiBaseURL = server.URL
+	client.SetAccessToken("token123")
+
+	metricResponse, err := client.FetchMetric(context.Background(), testRequest())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if metricResponse.Metric != "totalEnablements" {
+		t.Errorf("expected metric %q, got %q", "totalEnablements", metricResponse.Metric)
+	}
+	if metricResponse.Locale != "en-US" {
+		t.Errorf("expected locale to be copied from request, got %q", metricResponse.Locale)
+	}
+	if len(metricResponse.Values) != 1 || metricResponse.Values[0] != 1 {
+		t.Errorf("unexpected values: %+v", metricResponse.Values)
+	}
+}
+
+func TestFetchMetricRetriesOn500(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) < 3 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(`{"metric":"totalEnablements","timestamps":["2020-01-01T00:00:00Z"],"values":[1]}`))
+	}))
+	defer server.Close()
+
+	client := NewClient("id", "secret", "refresh")
+	client.HTTPClient = server.Client()
+	client.smapiBaseURL = server.URL
+	client.SetAccessToken("token123")
+
+	metricResponse, err := client.FetchMetric(context.Background(), testRequest())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if attempts != 3 {
+		t.Errorf("expected 3 attempts, got %d", attempts)
+	}
+	if metricResponse.Metric != "totalEnablements" {
+		t.Errorf("expected metric %q, got %q", "totalEnablements", metricResponse.Metric)
+	}
+}
+
+func TestFetchMetricReturnsSMAPIErrorOn4xx(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"message":"invalid request","violations":[{"code":"InvalidParameterValue","message":"bad period"}]}`))
+	}))
+	defer server.Close()
+
+	client := NewClient("id", "secret", "refresh")
+	client.HTTPClient = server.Client()
+	client.smapiBaseURL = server.URL
+	client.SetAccessToken("token123")
+
+	_, err := client.FetchMetric(context.Background(), testRequest())
+	var smapiErr *SMAPIError
+	if !errors.As(err, &smapiErr) {
+		t.Fatalf("expected *SMAPIError, got %T: %v", err, err)
+	}
+	if smapiErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d", http.StatusBadRequest, smapiErr.StatusCode)
+	}
+	if len(smapiErr.Violations) != 1 || smapiErr.Violations[0].Code != "InvalidParameterValue" {
+		t.Errorf("expected violation to be decoded, got %+v", smapiErr.Violations)
+	}
+}
+
+func TestFetchMetricRefreshesTokenOn401(t *testing.T) {
+	var lwaCalls int32
+
+	lwaServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&lwaCalls, 1)
+		w.Write([]byte(`{"access_token":"fresh-token","expires_in":3600,"token_type":"bearer"}`))
+	}))
+	defer lwaServer.Close()
+
+	smapiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer fresh-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"metric":"totalEnablements","timestamps":["2020-01-01T00:00:00Z"],"values":[1]}`))
+	}))
+	defer smapiServer.Close()
+
+	client := NewClient("id", "secret", "refresh")
+	client.HTTPClient = smapiServer.Client()
+	client.lwaOAuthURL = lwaServer.URL
+	client.smapiBaseURL = smapiServer.URL
+	client.SetAccessToken("stale-token")
+
+	metricResponse, err := client.FetchMetric(context.Background(), testRequest())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if lwaCalls != 1 {
+		t.Errorf("expected exactly 1 token refresh, got %d", lwaCalls)
+	}
+	if metricResponse.Metric != "totalEnablements" {
+		t.Errorf("expected metric %q, got %q", "totalEnablements", metricResponse.Metric)
+	}
+}
+
+func TestFetchMetrics(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"metric":"totalEnablements","timestamps":["2020-01-01T00:00:00Z"],"values":[1]}`))
+	}))
+	defer server.Close()
+
+	client := NewClient("id", "secret", "refresh")
+	client.HTTPClient = server.Client()
+	client.smapiBaseURL = server.URL
+	client.SetAccessToken("token123")
+
+	reqA, reqB := testRequest(), testRequest()
+	reqB.Locale = "en-GB"
+	reqs := []MetricRequest{reqA, reqB}
+
+	results, errs := client.FetchMetrics(context.Background(), reqs, 2)
+	if len(results) != 2 || len(errs) != 2 {
+		t.Fatalf("expected 2 results and 2 errs, got %d/%d", len(results), len(errs))
+	}
+	if errs[0] != nil || errs[1] != nil {
+		t.Fatalf("expected no errors, got %v / %v", errs[0], errs[1])
+	}
+	if results[0].Locale != "en-US" || results[1].Locale != "en-GB" {
+		t.Errorf("expected results in request order, got %+v", results)
+	}
+}
+
+func TestFetchMetricsPartialFailureDoesNotAbortOthers(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("metric") == "failedUtterances" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"message":"boom"}`))
+			return
+		}
+		w.Write([]byte(`{"metric":"totalEnablements","timestamps":["2020-01-01T00:00:00Z"],"values":[1]}`))
+	}))
+	defer server.Close()
+
+	client := NewClient("id", "secret", "refresh")
+	client.HTTPClient = server.Client()
+	client.smapiBaseURL = server.URL
+	client.SetAccessToken("token123")
+
+	ok, bad := testRequest(), testRequest()
+	bad.Metric = "failedUtterances"
+	reqs := []MetricRequest{ok, bad}
+
+	results, errs := client.FetchMetrics(context.Background(), reqs, 2)
+	if errs[0] != nil {
+		t.Errorf("expected first request to succeed, got %v", errs[0])
+	}
+	if errs[1] == nil {
+		t.Error("expected second request to fail")
+	}
+	if results[0].Metric != "totalEnablements" {
+		t.Errorf("expected first result populated, got %+v", results[0])
+	}
+}
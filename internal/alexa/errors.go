@@ -0,0 +1,37 @@
+package alexa
+
+import "fmt"
+
+// AuthError is returned when the LWA token endpoint rejects a request, e.g.
+// an expired or revoked refresh token.
+type AuthError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *AuthError) Error() string {
+	return fmt.Sprintf("lwa authentication failed: status %d: %s", e.StatusCode, e.Body)
+}
+
+// Violation is a single entry in the "violations" array SMAPI includes on
+// 4xx error responses.
+type Violation struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// SMAPIError is returned when a SMAPI request fails with a non-retryable
+// status code. It carries the Amazon error payload so callers can surface
+// the specific violation(s) rather than just a status code.
+type SMAPIError struct {
+	StatusCode int
+	Message    string      `json:"message"`
+	Violations []Violation `json:"violations"`
+}
+
+func (e *SMAPIError) Error() string {
+	if len(e.Violations) == 0 {
+		return fmt.Sprintf("smapi request failed: status %d: %s", e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("smapi request failed: status %d: %s (%d violations, first: %s)", e.StatusCode, e.Message, len(e.Violations), e.Violations[0].Message)
+}
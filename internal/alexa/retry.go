@@ -0,0 +1,99 @@
+package alexa
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	maxAttempts       = 4
+	retryBaseDelay    = 250 * time.Millisecond
+	retryMaxDelay     = 10 * time.Second
+	defaultRetryAfter = 5 * time.Second
+)
+
+// isRetryableStatus reports whether a response with this status code
+// represents a transient failure worth retrying: any 5xx, or 429 Too Many
+// Requests.
+func isRetryableStatus(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode >= 500
+}
+
+// retryDelay returns how long to wait before the next attempt (attempt is
+// 0-indexed: the attempt that just failed). It honors a Retry-After header
+// when present (SMAPI sends one on 429s), otherwise falls back to
+// exponential backoff with jitter.
+func retryDelay(resp *http.Response, attempt int) time.Duration {
+	if resp != nil {
+		if retryAfter := resp.Header.Get("Retry-After"); retryAfter != "" {
+			if seconds, err := strconv.Atoi(retryAfter); err == nil {
+				return time.Duration(seconds) * time.Second
+			}
+		}
+		if resp.StatusCode == http.StatusTooManyRequests {
+			return defaultRetryAfter
+		}
+	}
+
+	delay := retryBaseDelay * time.Duration(1<<uint(attempt))
+	if delay > retryMaxDelay {
+		delay = retryMaxDelay
+	}
+	jitter := time.Duration(rand.Int63n(int64(delay)/2 + 1))
+	return delay + jitter
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(d):
+		return nil
+	}
+}
+
+// doWithRetry executes the request built by newRequest, retrying transient
+// network errors and retryable status codes with exponential backoff and
+// jitter. newRequest is called once per attempt since a *http.Request can't
+// be reused after its body has been read.
+func (c *Client) doWithRetry(ctx context.Context, newRequest func() (*http.Request, error)) (*http.Response, error) {
+	var lastErr error
+
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		req, err := newRequest()
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := c.HTTPClient.Do(req)
+		if err != nil {
+			lastErr = err
+			if attempt == maxAttempts-1 {
+				break
+			}
+			if err := sleep(ctx, retryDelay(nil, attempt)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if isRetryableStatus(resp.StatusCode) && attempt < maxAttempts-1 {
+			delay := retryDelay(resp, attempt)
+			resp.Body.Close()
+			lastErr = fmt.Errorf("retryable status %d", resp.StatusCode)
+
+			if err := sleep(ctx, delay); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		return resp, nil
+	}
+
+	return nil, lastErr
+}
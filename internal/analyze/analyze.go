@@ -0,0 +1,162 @@
+// Package analyze scans fetched Alexa metrics for anomalies before they're
+// rendered, so a regression in skill health can fail the Bitrise step
+// instead of silently shipping a chart nobody looks at.
+package analyze
+
+import (
+	"sort"
+
+	"github.com/ChargePoint/bitrise-step-alexa-metrics/internal/alexa"
+)
+
+// Severity classifies how far an anomaly deviates from the expected range.
+// Only "critical" anomalies are eligible to fail the build.
+type Severity string
+
+const (
+	SeverityWarning  Severity = "warning"
+	SeverityCritical Severity = "critical"
+)
+
+// Anomaly is a single data point (or window aggregate) that fell outside
+// its expected range.
+type Anomaly struct {
+	Metric       string   `json:"metric"`
+	Locale       string   `json:"locale,omitempty"`
+	Stage        string   `json:"stage,omitempty"`
+	Timestamp    string   `json:"timestamp"`
+	Value        float64  `json:"value"`
+	ExpectedLow  *float64 `json:"expectedLow,omitempty"`
+	ExpectedHigh *float64 `json:"expectedHigh,omitempty"`
+	Severity     Severity `json:"severity"`
+	Reason       string   `json:"reason"`
+}
+
+// Thresholds holds the simple, metric-specific pass/fail bounds checked
+// across the whole fetched window. A nil field means that check is
+// disabled.
+type Thresholds struct {
+	FailIfFailedUtterancesGT *float64
+	FailIfSuccessRateLT      *float64
+}
+
+// Analyze runs both detectors (fixed thresholds and rolling MAD) over
+// metricResponses and returns every anomaly found: threshold breaches
+// first, then MAD anomalies in chronological order within each
+// metric/locale/stage series. metricResponses is expected to already be
+// scoped to one series per (metric, locale, stage) combination, as
+// produced by alexa.Client.FetchMetrics.
+func Analyze(metricResponses []alexa.MetricsResponse, thresholds Thresholds, madK float64) []Anomaly {
+	var anomalies []Anomaly
+
+	anomalies = append(anomalies, thresholdAnomalies(metricResponses, thresholds)...)
+
+	for _, metricResponse := range metricResponses {
+		anomalies = append(anomalies, madAnomalies(metricResponse, madK)...)
+	}
+
+	return anomalies
+}
+
+// HasCritical reports whether any anomaly in anomalies is SeverityCritical.
+func HasCritical(anomalies []Anomaly) bool {
+	for _, a := range anomalies {
+		if a.Severity == SeverityCritical {
+			return true
+		}
+	}
+	return false
+}
+
+type seriesKey struct {
+	locale string
+	stage  string
+}
+
+// thresholdAnomalies evaluates Thresholds across the fetched window,
+// aggregating each (locale, stage) group's series so that e.g. success
+// rate can be computed from both successfulSessions and totalSessions.
+func thresholdAnomalies(metricResponses []alexa.MetricsResponse, thresholds Thresholds) []Anomaly {
+	byGroup := make(map[seriesKey]map[string]alexa.MetricsResponse)
+	var groupOrder []seriesKey
+
+	for _, metricResponse := range metricResponses {
+		key := seriesKey{locale: metricResponse.Locale, stage: metricResponse.Stage}
+		if _, ok := byGroup[key]; !ok {
+			byGroup[key] = make(map[string]alexa.MetricsResponse)
+			groupOrder = append(groupOrder, key)
+		}
+		byGroup[key][metricResponse.Metric] = metricResponse
+	}
+
+	sort.Slice(groupOrder, func(i, j int) bool {
+		if groupOrder[i].locale != groupOrder[j].locale {
+			return groupOrder[i].locale < groupOrder[j].locale
+		}
+		return groupOrder[i].stage < groupOrder[j].stage
+	})
+
+	var anomalies []Anomaly
+
+	for _, key := range groupOrder {
+		byMetric := byGroup[key]
+
+		if thresholds.FailIfFailedUtterancesGT != nil {
+			if failed, ok := byMetric["failedUtterances"]; ok {
+				total := sum(failed.Values)
+				if total > *thresholds.FailIfFailedUtterancesGT {
+					anomalies = append(anomalies, Anomaly{
+						Metric:       "failedUtterances",
+						Locale:       key.locale,
+						Stage:        key.stage,
+						Timestamp:    lastTimestamp(failed),
+						Value:        total,
+						ExpectedHigh: thresholds.FailIfFailedUtterancesGT,
+						Severity:     SeverityCritical,
+						Reason:       "total failedUtterances over window exceeded threshold",
+					})
+				}
+			}
+		}
+
+		if thresholds.FailIfSuccessRateLT != nil {
+			successful, hasSuccessful := byMetric["successfulSessions"]
+			total, hasTotal := byMetric["totalSessions"]
+			if hasSuccessful && hasTotal {
+				totalSessions := sum(total.Values)
+				if totalSessions > 0 {
+					rate := sum(successful.Values) / totalSessions
+					if rate < *thresholds.FailIfSuccessRateLT {
+						anomalies = append(anomalies, Anomaly{
+							Metric:      "successRate",
+							Locale:      key.locale,
+							Stage:       key.stage,
+							Timestamp:   lastTimestamp(total),
+							Value:       rate,
+							ExpectedLow: thresholds.FailIfSuccessRateLT,
+							Severity:    SeverityCritical,
+							Reason:      "successfulSessions/totalSessions over window fell below threshold",
+						})
+					}
+				}
+			}
+		}
+	}
+
+	return anomalies
+}
+
+func sum(values []float64) float64 {
+	var total float64
+	for _, v := range values {
+		total += v
+	}
+	return total
+}
+
+func lastTimestamp(metricResponse alexa.MetricsResponse) string {
+	if len(metricResponse.Timestamps) == 0 {
+		return ""
+	}
+	return metricResponse.Timestamps[len(metricResponse.Timestamps)-1]
+}
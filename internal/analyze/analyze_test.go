@@ -0,0 +1,93 @@
+package analyze
+
+import (
+	"testing"
+
+	"github.com/ChargePoint/bitrise-step-alexa-metrics/internal/alexa"
+)
+
+func timestamps(n int) []string {
+	ts := make([]string, n)
+	days := []string{"01", "02", "03", "04", "05", "06", "07", "08", "09", "10"}
+	for i := 0; i < n; i++ {
+		ts[i] = "2020-01-" + days[i%len(days)] + "T00:00:00Z"
+	}
+	return ts
+}
+
+func TestMADAnomaliesFlagsSpike(t *testing.T) {
+	values := []float64{10, 11, 9, 10, 11, 10, 9, 100}
+	metricResponse := alexa.MetricsResponse{
+		Metric:     "totalEnablements",
+		Locale:     "en-US",
+		Stage:      "live",
+		Timestamps: timestamps(len(values)),
+		Values:     values,
+	}
+
+	anomalies := madAnomalies(metricResponse, 3.5)
+
+	if len(anomalies) != 1 {
+		t.Fatalf("expected 1 anomaly, got %d: %+v", len(anomalies), anomalies)
+	}
+	if anomalies[0].Value != 100 {
+		t.Errorf("expected anomaly value 100, got %v", anomalies[0].Value)
+	}
+	if anomalies[0].Severity != SeverityCritical {
+		t.Errorf("expected critical severity for a large spike, got %v", anomalies[0].Severity)
+	}
+}
+
+func TestMADAnomaliesNoHistoryNoAnomalies(t *testing.T) {
+	metricResponse := alexa.MetricsResponse{
+		Metric:     "totalEnablements",
+		Timestamps: timestamps(2),
+		Values:     []float64{10, 1000},
+	}
+
+	if anomalies := madAnomalies(metricResponse, 3.5); len(anomalies) != 0 {
+		t.Errorf("expected no anomalies with insufficient history, got %+v", anomalies)
+	}
+}
+
+func TestThresholdAnomaliesFailedUtterances(t *testing.T) {
+	limit := 100.0
+	metricResponses := []alexa.MetricsResponse{
+		{Metric: "failedUtterances", Locale: "en-US", Stage: "live", Timestamps: timestamps(2), Values: []float64{60, 60}},
+	}
+
+	anomalies := thresholdAnomalies(metricResponses, Thresholds{FailIfFailedUtterancesGT: &limit})
+
+	if len(anomalies) != 1 {
+		t.Fatalf("expected 1 anomaly, got %d", len(anomalies))
+	}
+	if anomalies[0].Severity != SeverityCritical {
+		t.Errorf("expected threshold breach to be critical, got %v", anomalies[0].Severity)
+	}
+}
+
+func TestThresholdAnomaliesSuccessRate(t *testing.T) {
+	minRate := 0.9
+	metricResponses := []alexa.MetricsResponse{
+		{Metric: "successfulSessions", Locale: "en-US", Stage: "live", Timestamps: timestamps(2), Values: []float64{5, 5}},
+		{Metric: "totalSessions", Locale: "en-US", Stage: "live", Timestamps: timestamps(2), Values: []float64{10, 10}},
+	}
+
+	anomalies := thresholdAnomalies(metricResponses, Thresholds{FailIfSuccessRateLT: &minRate})
+
+	if len(anomalies) != 1 {
+		t.Fatalf("expected 1 anomaly, got %d", len(anomalies))
+	}
+	if anomalies[0].Value != 0.5 {
+		t.Errorf("expected success rate 0.5, got %v", anomalies[0].Value)
+	}
+}
+
+func TestHasCritical(t *testing.T) {
+	if HasCritical(nil) {
+		t.Error("expected no critical anomalies in an empty slice")
+	}
+	if !HasCritical([]Anomaly{{Severity: SeverityWarning}, {Severity: SeverityCritical}}) {
+		t.Error("expected a critical anomaly to be detected")
+	}
+}
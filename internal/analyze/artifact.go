@@ -0,0 +1,24 @@
+package analyze
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// WriteArtifact writes anomalies as anomalies.json under deployDir.
+func WriteArtifact(deployDir string, anomalies []Anomaly) error {
+	f, err := os.Create(filepath.Join(deployDir, "anomalies.json"))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if anomalies == nil {
+		anomalies = []Anomaly{}
+	}
+
+	encoder := json.NewEncoder(f)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(anomalies)
+}
@@ -0,0 +1,91 @@
+package analyze
+
+import (
+	"sort"
+
+	"github.com/ChargePoint/bitrise-step-alexa-metrics/internal/alexa"
+)
+
+// minHistory is the smallest trailing history a point needs before it's
+// eligible to be flagged; fewer points than this make the median/MAD too
+// noisy to be meaningful.
+const minHistory = 3
+
+// criticalMADMultiple is how far past k*MAD a deviation has to be before an
+// anomaly is escalated from warning to critical.
+const criticalMADMultiple = 2.0
+
+// madAnomalies flags points in metricResponse whose deviation from the
+// trailing median exceeds k times the trailing median absolute deviation
+// (MAD). MAD is used instead of standard deviation because Alexa daily
+// counts are heavy-tailed, and a MAD is far less skewed by the outliers
+// it's trying to detect.
+func madAnomalies(metricResponse alexa.MetricsResponse, k float64) []Anomaly {
+	values := metricResponse.Values
+	var anomalies []Anomaly
+
+	for i := minHistory; i < len(values); i++ {
+		history := values[:i]
+		median := median(history)
+		mad := medianAbsoluteDeviation(history, median)
+		if mad == 0 {
+			continue
+		}
+
+		deviation := values[i] - median
+		absDeviation := deviation
+		if absDeviation < 0 {
+			absDeviation = -absDeviation
+		}
+
+		threshold := k * mad
+		if absDeviation <= threshold {
+			continue
+		}
+
+		severity := SeverityWarning
+		if absDeviation > criticalMADMultiple*threshold {
+			severity = SeverityCritical
+		}
+
+		expectedLow := median - threshold
+		expectedHigh := median + threshold
+
+		anomalies = append(anomalies, Anomaly{
+			Metric:       metricResponse.Metric,
+			Locale:       metricResponse.Locale,
+			Stage:        metricResponse.Stage,
+			Timestamp:    metricResponse.Timestamps[i],
+			Value:        values[i],
+			ExpectedLow:  &expectedLow,
+			ExpectedHigh: &expectedHigh,
+			Severity:     severity,
+			Reason:       "trailing deviation exceeded k*MAD",
+		})
+	}
+
+	return anomalies
+}
+
+func median(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+func medianAbsoluteDeviation(values []float64, center float64) float64 {
+	deviations := make([]float64, len(values))
+	for i, v := range values {
+		d := v - center
+		if d < 0 {
+			d = -d
+		}
+		deviations[i] = d
+	}
+	return median(deviations)
+}
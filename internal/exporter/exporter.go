@@ -0,0 +1,150 @@
+// Package exporter publishes fetched Alexa metrics to external monitoring
+// backends (Prometheus Pushgateway today, more Sinks later) so CI
+// dashboards can trend skill health over time without persisting the PNG
+// charts.
+package exporter
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ChargePoint/bitrise-step-alexa-metrics/internal/alexa"
+)
+
+// Sample is a single Alexa metric data point, labeled the way the
+// Pushgateway (and any future Sink) needs in order to tell series apart.
+type Sample struct {
+	Metric    string
+	SkillID   string
+	Locale    string
+	Stage     string
+	Timestamp time.Time
+	Value     float64
+}
+
+// Sink publishes a batch of samples to a monitoring backend.
+type Sink interface {
+	Push(samples []Sample) error
+}
+
+// SamplesFromMetricsResponses flattens the per-metric time series fetched
+// from SMAPI into the individual (metric, locale, stage, timestamp) samples
+// a Sink pushes.
+func SamplesFromMetricsResponses(skillID string, metricResponses []alexa.MetricsResponse) ([]Sample, error) {
+	var samples []Sample
+
+	for _, metricResponse := range metricResponses {
+		for i, value := range metricResponse.Values {
+			timestamp, err := time.Parse(time.RFC3339, metricResponse.Timestamps[i])
+			if err != nil {
+				return nil, fmt.Errorf("parsing timestamp for metric %s: %w", metricResponse.Metric, err)
+			}
+
+			samples = append(samples, Sample{
+				Metric:    metricResponse.Metric,
+				SkillID:   skillID,
+				Locale:    metricResponse.Locale,
+				Stage:     metricResponse.Stage,
+				Timestamp: timestamp,
+				Value:     value,
+			})
+		}
+	}
+
+	return samples, nil
+}
+
+// gaugeName turns a SMAPI metric name like "totalEnablements" into a
+// Prometheus-style gauge name.
+func gaugeName(metric string) string {
+	return "alexa_skill_" + toSnakeCase(metric)
+}
+
+func toSnakeCase(s string) string {
+	var b strings.Builder
+	for i, r := range s {
+		if r >= 'A' && r <= 'Z' {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			b.WriteRune(r - 'A' + 'a')
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// seriesKey identifies one label set (one exposition line) within a gauge:
+// Pushgateway holds last-value-per-label-set, so a label set must appear at
+// most once per push.
+type seriesKey struct {
+	metric  string
+	skillID string
+	locale  string
+	stage   string
+}
+
+// latestPerSeries collapses samples down to the most recent sample for each
+// (metric, locale, stage) combination. A fetched window holds many
+// timestamps per series, but OpenMetrics forbids repeating a label set
+// within a MetricFamily, so only the latest point can be exposed per push.
+func latestPerSeries(samples []Sample) []Sample {
+	latest := make(map[seriesKey]Sample)
+	var order []seriesKey
+	for _, sample := range samples {
+		key := seriesKey{metric: sample.Metric, skillID: sample.SkillID, locale: sample.Locale, stage: sample.Stage}
+		existing, ok := latest[key]
+		if !ok {
+			order = append(order, key)
+		}
+		if !ok || sample.Timestamp.After(existing.Timestamp) {
+			latest[key] = sample
+		}
+	}
+
+	result := make([]Sample, len(order))
+	for i, key := range order {
+		result[i] = latest[key]
+	}
+	return result
+}
+
+// formatOpenMetrics renders samples as OpenMetrics text exposition format:
+// one gauge per metric name, with skill_id/locale/stage labels. Only the
+// latest sample per (metric, locale, stage) series is emitted, and the
+// per-point timestamp is omitted so Prometheus stamps the push with scrape
+// time, matching how Pushgateway is meant to be used (last-value-per-job,
+// not a historical backfill).
+func formatOpenMetrics(samples []Sample) string {
+	samples = latestPerSeries(samples)
+
+	byMetric := make(map[string][]Sample)
+	var metricNames []string
+	for _, sample := range samples {
+		if _, ok := byMetric[sample.Metric]; !ok {
+			metricNames = append(metricNames, sample.Metric)
+		}
+		byMetric[sample.Metric] = append(byMetric[sample.Metric], sample)
+	}
+	sort.Strings(metricNames)
+
+	var b strings.Builder
+	for _, metric := range metricNames {
+		name := gaugeName(metric)
+		fmt.Fprintf(&b, "# TYPE %s gauge\n", name)
+		for _, sample := range byMetric[metric] {
+			fmt.Fprintf(&b, "%s{skill_id=%q,locale=%q,stage=%q} %s\n",
+				name, sample.SkillID, sample.Locale, sample.Stage, formatValue(sample.Value))
+		}
+	}
+	b.WriteString("# EOF\n")
+
+	return b.String()
+}
+
+func formatValue(v float64) string {
+	return fmt.Sprintf("%g", v)
+}
@@ -0,0 +1,141 @@
+package exporter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ChargePoint/bitrise-step-alexa-metrics/internal/alexa"
+)
+
+func testSamples() []Sample {
+	return []Sample{
+		{Metric: "totalEnablements", SkillID: "skill1", Locale: "en-US", Stage: "live", Timestamp: time.Unix(1600000000, 0).UTC(), Value: 42},
+	}
+}
+
+func TestSamplesFromMetricsResponses(t *testing.T) {
+	metricResponses := []alexa.MetricsResponse{
+		{
+			Metric:     "totalEnablements",
+			Locale:     "en-US",
+			Stage:      "live",
+			Timestamps: []string{"2020-09-13T12:26:40Z"},
+			Values:     []float64{42},
+		},
+	}
+
+	samples, err := SamplesFromMetricsResponses("skill1", metricResponses)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(samples) != 1 {
+		t.Fatalf("expected 1 sample, got %d", len(samples))
+	}
+	if samples[0].SkillID != "skill1" || samples[0].Value != 42 {
+		t.Errorf("unexpected sample: %+v", samples[0])
+	}
+}
+
+func TestFormatOpenMetrics(t *testing.T) {
+	text := formatOpenMetrics(testSamples())
+
+	if !strings.Contains(text, "# TYPE alexa_skill_total_enablements gauge") {
+		t.Errorf("expected gauge type line, got:\n%s", text)
+	}
+	if !strings.Contains(text, `alexa_skill_total_enablements{skill_id="skill1",locale="en-US",stage="live"} 42`) {
+		t.Errorf("expected sample line, got:\n%s", text)
+	}
+	if !strings.HasSuffix(text, "# EOF\n") {
+		t.Errorf("expected OpenMetrics EOF marker, got:\n%s", text)
+	}
+}
+
+func TestFormatOpenMetricsDedupesToLatestPerSeries(t *testing.T) {
+	samples := []Sample{
+		{Metric: "totalEnablements", SkillID: "skill1", Locale: "en-US", Stage: "live", Timestamp: time.Unix(1600000000, 0).UTC(), Value: 1},
+		{Metric: "totalEnablements", SkillID: "skill1", Locale: "en-US", Stage: "live", Timestamp: time.Unix(1600086400, 0).UTC(), Value: 2},
+	}
+
+	text := formatOpenMetrics(samples)
+
+	if strings.Count(text, "alexa_skill_total_enablements{") != 1 {
+		t.Fatalf("expected exactly one line for the repeated label set, got:\n%s", text)
+	}
+	if !strings.Contains(text, `alexa_skill_total_enablements{skill_id="skill1",locale="en-US",stage="live"} 2`) {
+		t.Errorf("expected only the latest value to be exposed, got:\n%s", text)
+	}
+}
+
+func TestFormatOpenMetricsKeepsSeriesFromDifferentSkills(t *testing.T) {
+	samples := []Sample{
+		{Metric: "totalEnablements", SkillID: "skill1", Locale: "en-US", Stage: "live", Timestamp: time.Unix(1600000000, 0).UTC(), Value: 1},
+		{Metric: "totalEnablements", SkillID: "skill2", Locale: "en-US", Stage: "live", Timestamp: time.Unix(1600000000, 0).UTC(), Value: 2},
+	}
+
+	text := formatOpenMetrics(samples)
+
+	if !strings.Contains(text, `alexa_skill_total_enablements{skill_id="skill1",locale="en-US",stage="live"} 1`) {
+		t.Errorf("expected skill1's series to be kept, got:\n%s", text)
+	}
+	if !strings.Contains(text, `alexa_skill_total_enablements{skill_id="skill2",locale="en-US",stage="live"} 2`) {
+		t.Errorf("expected skill2's series to be kept, got:\n%s", text)
+	}
+}
+
+func TestFileSinkPush(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metrics.prom")
+	sink := NewFileSink(path)
+
+	if err := sink.Push(testSamples()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error reading file: %v", err)
+	}
+	if !strings.Contains(string(data), "alexa_skill_total_enablements") {
+		t.Errorf("expected file to contain gauge, got:\n%s", data)
+	}
+}
+
+func TestPushgatewaySinkPush(t *testing.T) {
+	var gotPath, gotAuth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotAuth = r.Header.Get("Authorization")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	sink := NewPushgatewaySink(server.URL, "alexa-metrics")
+	sink.BearerToken = "secret-token"
+
+	if err := sink.Push(testSamples()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if gotPath != "/metrics/job/alexa-metrics" {
+		t.Errorf("expected job path, got %q", gotPath)
+	}
+	if gotAuth != "Bearer secret-token" {
+		t.Errorf("expected bearer auth header, got %q", gotAuth)
+	}
+}
+
+func TestPushgatewaySinkPushError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	sink := NewPushgatewaySink(server.URL, "alexa-metrics")
+
+	if err := sink.Push(testSamples()); err == nil {
+		t.Error("expected error for non-2xx response")
+	}
+}
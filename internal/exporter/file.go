@@ -0,0 +1,22 @@
+package exporter
+
+import "os"
+
+// FileSink writes samples to a local file in OpenMetrics text format
+// instead of (or in addition to) pushing them to a remote backend. Useful
+// for archiving alongside the PNG charts, or for testing without a live
+// Pushgateway.
+type FileSink struct {
+	Path string
+}
+
+// NewFileSink returns a FileSink that writes to path.
+func NewFileSink(path string) *FileSink {
+	return &FileSink{Path: path}
+}
+
+// Push overwrites the sink's file with samples rendered as OpenMetrics
+// text.
+func (s *FileSink) Push(samples []Sample) error {
+	return os.WriteFile(s.Path, []byte(formatOpenMetrics(samples)), 0644)
+}
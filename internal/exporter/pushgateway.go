@@ -0,0 +1,67 @@
+package exporter
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// PushgatewaySink pushes samples to a Prometheus Pushgateway as a single
+// grouping under Job, once per call to Push.
+type PushgatewaySink struct {
+	HTTPClient *http.Client
+
+	// URL is the Pushgateway base URL, e.g. "https://pushgateway.example.com".
+	URL string
+	Job string
+
+	// BasicAuthUser/BasicAuthPass and BearerToken are mutually exclusive;
+	// set at most one. Leave both empty to push unauthenticated.
+	BasicAuthUser string
+	BasicAuthPass string
+	BearerToken   string
+}
+
+// NewPushgatewaySink returns a PushgatewaySink with a sane default HTTP
+// timeout.
+func NewPushgatewaySink(url, job string) *PushgatewaySink {
+	return &PushgatewaySink{
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+		URL:        url,
+		Job:        job,
+	}
+}
+
+// Push publishes samples to the configured Pushgateway job. It POSTs rather
+// than PUTs, so it adds to (rather than replaces) any other groupings
+// already pushed under the same job.
+func (s *PushgatewaySink) Push(samples []Sample) error {
+	body := formatOpenMetrics(samples)
+
+	endpoint := strings.TrimRight(s.URL, "/") + "/metrics/job/" + s.Job
+	req, err := http.NewRequest("POST", endpoint, strings.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/openmetrics-text; version=1.0.0; charset=utf-8")
+
+	switch {
+	case s.BearerToken != "":
+		req.Header.Set("Authorization", "Bearer "+s.BearerToken)
+	case s.BasicAuthUser != "":
+		req.SetBasicAuth(s.BasicAuthUser, s.BasicAuthPass)
+	}
+
+	resp, err := s.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("pushgateway returned status %d", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,120 @@
+// Package report renders the PNG charts, HTML dashboard, and machine
+// readable (CSV/JSON) artifacts produced from fetched Alexa metrics.
+package report
+
+import (
+	"os"
+	"time"
+
+	chart "github.com/wcharczuk/go-chart/v2"
+	"github.com/wcharczuk/go-chart/v2/drawing"
+
+	"github.com/ChargePoint/bitrise-step-alexa-metrics/internal/alexa"
+	"github.com/ChargePoint/bitrise-step-alexa-metrics/internal/analyze"
+)
+
+// seriesLabel names a chart line for a metric fetched for a given
+// locale/stage combination. When a metric was only fetched once (the
+// common case of a single locale and stage), the label is just the
+// metric name.
+func seriesLabel(metricName string, metricResponse alexa.MetricsResponse, combined bool) string {
+	if !combined {
+		return metricName
+	}
+	return metricResponse.Locale + "/" + metricResponse.Stage
+}
+
+// anomalyAnnotations builds a red-marker AnnotationSeries for every anomaly
+// in anomalies that belongs to metricName, so regressions are visible on
+// the chart itself and not just in anomalies.json.
+func anomalyAnnotations(metricName string, anomalies []analyze.Anomaly) chart.Series {
+	var points []chart.Value2
+	for _, a := range anomalies {
+		if a.Metric != metricName {
+			continue
+		}
+		timestamp, err := time.Parse(time.RFC3339, a.Timestamp)
+		if err != nil {
+			continue
+		}
+		points = append(points, chart.Value2{
+			XValue: chart.TimeToFloat64(timestamp),
+			YValue: a.Value,
+			Label:  string(a.Severity),
+		})
+	}
+	if len(points) == 0 {
+		return nil
+	}
+
+	return chart.AnnotationSeries{
+		Name:        "anomalies",
+		Annotations: points,
+		Style: chart.Style{
+			StrokeColor: drawing.ColorRed,
+			FillColor:   drawing.ColorRed,
+		},
+	}
+}
+
+// GenerateMetricChart renders one PNG line chart per metric under deployDir,
+// with one line per entry in series and a red marker at every anomaly
+// detected for metricName. Every entry in series must share the same
+// Metric; entries are distinguished on the chart by Locale/Stage. Returns
+// the path to the file it wrote.
+func GenerateMetricChart(deployDir, metricName string, series []alexa.MetricsResponse, anomalies []analyze.Anomaly) (string, error) {
+	combined := len(series) > 1
+
+	chartSeries := make([]chart.Series, 0, len(series)+1)
+	for i, metricResponse := range series {
+		count := len(metricResponse.Values)
+		xValues := make([]time.Time, count)
+		for j := 0; j < count; j++ {
+			xValues[j], _ = time.Parse(time.RFC3339, metricResponse.Timestamps[j])
+		}
+
+		color := chart.GetDefaultColor(i)
+		chartSeries = append(chartSeries, chart.TimeSeries{
+			Name:    seriesLabel(metricName, metricResponse, combined),
+			XValues: xValues,
+			YValues: metricResponse.Values,
+			Style: chart.Style{
+				StrokeColor: color,
+				FillColor:   color.WithAlpha(0),
+			},
+		})
+	}
+
+	if annotations := anomalyAnnotations(metricName, anomalies); annotations != nil {
+		chartSeries = append(chartSeries, annotations)
+	}
+
+	graph := chart.Chart{
+		Background: chart.Style{
+			Padding: chart.NewBox(20, 20, 20, 20),
+		},
+		XAxis: chart.XAxis{
+			Name: "Time",
+		},
+		YAxis: chart.YAxis{
+			Name: "Value",
+		},
+		Series: chartSeries,
+	}
+	if combined {
+		graph.Elements = []chart.Renderable{chart.Legend(&graph)}
+	}
+
+	filepath := deployDir + "/" + metricName + ".png"
+	f, err := os.Create(filepath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	if err := graph.Render(chart.PNG, f); err != nil {
+		return "", err
+	}
+
+	return filepath, nil
+}
@@ -0,0 +1,73 @@
+package report
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ChargePoint/bitrise-step-alexa-metrics/internal/alexa"
+	"github.com/ChargePoint/bitrise-step-alexa-metrics/internal/analyze"
+)
+
+func TestGenerateMetricChart(t *testing.T) {
+	deployDir := t.TempDir()
+	series := []alexa.MetricsResponse{
+		{
+			Metric:     "totalEnablements",
+			Locale:     "en-US",
+			Stage:      "live",
+			Timestamps: []string{"2020-09-13T00:00:00Z", "2020-09-14T00:00:00Z", "2020-09-15T00:00:00Z"},
+			Values:     []float64{1, 5, 3},
+		},
+	}
+	anomalies := []analyze.Anomaly{
+		{Metric: "totalEnablements", Timestamp: "2020-09-14T00:00:00Z", Value: 5, Severity: analyze.SeverityWarning},
+	}
+
+	chartFile, err := GenerateMetricChart(deployDir, "totalEnablements", series, anomalies)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	wantFile := filepath.Join(deployDir, "totalEnablements.png")
+	if chartFile != wantFile {
+		t.Errorf("expected chart file %s, got %s", wantFile, chartFile)
+	}
+
+	info, err := os.Stat(chartFile)
+	if err != nil {
+		t.Fatalf("expected chart file to exist: %v", err)
+	}
+	if info.Size() == 0 {
+		t.Error("expected chart file to be non-empty")
+	}
+}
+
+func TestGenerateMetricChartCombinedSeries(t *testing.T) {
+	deployDir := t.TempDir()
+	series := []alexa.MetricsResponse{
+		{
+			Metric:     "totalEnablements",
+			Locale:     "en-US",
+			Stage:      "live",
+			Timestamps: []string{"2020-09-13T00:00:00Z", "2020-09-14T00:00:00Z"},
+			Values:     []float64{1, 2},
+		},
+		{
+			Metric:     "totalEnablements",
+			Locale:     "en-GB",
+			Stage:      "live",
+			Timestamps: []string{"2020-09-13T00:00:00Z", "2020-09-14T00:00:00Z"},
+			Values:     []float64{3, 4},
+		},
+	}
+
+	chartFile, err := GenerateMetricChart(deployDir, "totalEnablements", series, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if info, err := os.Stat(chartFile); err != nil || info.Size() == 0 {
+		t.Fatalf("expected non-empty chart file, stat err: %v", err)
+	}
+}
@@ -0,0 +1,155 @@
+package report
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"html/template"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/ChargePoint/bitrise-step-alexa-metrics/internal/alexa"
+)
+
+// dashboardTemplate renders the per-run HTML report: a summary table
+// followed by the PNG chart for each metric.
+var dashboardTemplate = template.Must(template.New("dashboard").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Alexa Skill Metrics</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 0.4em 0.8em; text-align: right; }
+th:first-child, td:first-child { text-align: left; }
+img { max-width: 100%; margin-bottom: 2em; }
+</style>
+</head>
+<body>
+<h1>Alexa Skill Metrics</h1>
+<table>
+<tr><th>Metric</th><th>Locale</th><th>Stage</th><th>Min</th><th>Max</th><th>Avg</th><th>Last</th><th>WoW Delta</th></tr>
+{{range .Summaries}}<tr>
+<td>{{.Metric}}</td>
+<td>{{.Locale}}</td>
+<td>{{.Stage}}</td>
+<td>{{printf "%.2f" .Min}}</td>
+<td>{{printf "%.2f" .Max}}</td>
+<td>{{printf "%.2f" .Avg}}</td>
+<td>{{printf "%.2f" .Last}}</td>
+<td>{{printf "%.2f" .WoWDelta}}</td>
+</tr>
+{{end}}</table>
+{{range .Charts}}<h2>{{.Metric}}</h2>
+<img src="{{.File}}" alt="{{.Metric}} chart">
+{{end}}
+</body>
+</html>
+`))
+
+// chartEntry is one row of the "one image per metric" section of the
+// dashboard; it's kept distinct from Summary since a single chart can cover
+// several Summary rows (one per locale/stage combination).
+type chartEntry struct {
+	Metric string
+	File   string
+}
+
+type dashboardData struct {
+	Summaries []Summary
+	Charts    []chartEntry
+}
+
+// GenerateDashboard writes index.html, metrics.json, and metrics.csv into
+// deployDir, summarizing metricResponses (one entry per metric/locale/stage
+// combination that was fetched). chartFiles maps metric name to the path of
+// its rendered PNG (as produced by GenerateMetricChart, one chart per metric
+// with a line per locale/stage); entries are optional, a metric without a
+// chart is still listed in the summary table.
+func GenerateDashboard(deployDir string, metricResponses []alexa.MetricsResponse, chartFiles map[string]string) error {
+	summaries := make([]Summary, 0, len(metricResponses))
+	charts := make([]chartEntry, 0, len(chartFiles))
+	seenCharts := make(map[string]bool, len(chartFiles))
+
+	for _, metricResponse := range metricResponses {
+		summary := ComputeSummary(metricResponse)
+		if chartFile, ok := chartFiles[metricResponse.Metric]; ok {
+			summary.ChartFile = filepath.Base(chartFile)
+			if !seenCharts[metricResponse.Metric] {
+				seenCharts[metricResponse.Metric] = true
+				charts = append(charts, chartEntry{Metric: metricResponse.Metric, File: summary.ChartFile})
+			}
+		}
+		summaries = append(summaries, summary)
+	}
+
+	data := dashboardData{Summaries: summaries, Charts: charts}
+
+	if err := writeHTML(deployDir, data); err != nil {
+		return err
+	}
+	if err := writeJSON(deployDir, summaries); err != nil {
+		return err
+	}
+	return writeCSV(deployDir, summaries)
+}
+
+func writeHTML(deployDir string, data dashboardData) error {
+	f, err := os.Create(filepath.Join(deployDir, "index.html"))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return dashboardTemplate.Execute(f, data)
+}
+
+func writeJSON(deployDir string, summaries []Summary) error {
+	f, err := os.Create(filepath.Join(deployDir, "metrics.json"))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	encoder := json.NewEncoder(f)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(summaries)
+}
+
+func writeCSV(deployDir string, summaries []Summary) error {
+	f, err := os.Create(filepath.Join(deployDir, "metrics.csv"))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	writer := csv.NewWriter(f)
+	defer writer.Flush()
+
+	if err := writer.Write([]string{"metric", "locale", "stage", "min", "max", "avg", "last", "wow_delta"}); err != nil {
+		return err
+	}
+
+	for _, summary := range summaries {
+		row := []string{
+			summary.Metric,
+			summary.Locale,
+			summary.Stage,
+			formatFloat(summary.Min),
+			formatFloat(summary.Max),
+			formatFloat(summary.Avg),
+			formatFloat(summary.Last),
+			formatFloat(summary.WoWDelta),
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
@@ -0,0 +1,106 @@
+package report
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ChargePoint/bitrise-step-alexa-metrics/internal/alexa"
+)
+
+func testMetricResponses() []alexa.MetricsResponse {
+	return []alexa.MetricsResponse{
+		{
+			Metric:     "totalEnablements",
+			Locale:     "en-US",
+			Stage:      "live",
+			Timestamps: []string{"2020-09-13T00:00:00Z", "2020-09-14T00:00:00Z"},
+			Values:     []float64{1, 5},
+		},
+	}
+}
+
+func TestGenerateDashboard(t *testing.T) {
+	deployDir := t.TempDir()
+	metricResponses := testMetricResponses()
+	chartFiles := map[string]string{"totalEnablements": filepath.Join(deployDir, "totalEnablements.png")}
+
+	if err := GenerateDashboard(deployDir, metricResponses, chartFiles); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	html, err := os.ReadFile(filepath.Join(deployDir, "index.html"))
+	if err != nil {
+		t.Fatalf("expected index.html to be written: %v", err)
+	}
+	if !strings.Contains(string(html), "totalEnablements") {
+		t.Errorf("expected index.html to mention the metric, got:\n%s", html)
+	}
+	if !strings.Contains(string(html), `src="totalEnablements.png"`) {
+		t.Errorf("expected index.html to reference the chart file, got:\n%s", html)
+	}
+
+	jsonBytes, err := os.ReadFile(filepath.Join(deployDir, "metrics.json"))
+	if err != nil {
+		t.Fatalf("expected metrics.json to be written: %v", err)
+	}
+	var summaries []Summary
+	if err := json.Unmarshal(jsonBytes, &summaries); err != nil {
+		t.Fatalf("failed to parse metrics.json: %v", err)
+	}
+	if len(summaries) != 1 {
+		t.Fatalf("expected 1 summary, got %d", len(summaries))
+	}
+	if summaries[0].Metric != "totalEnablements" || summaries[0].ChartFile != "totalEnablements.png" {
+		t.Errorf("unexpected summary: %+v", summaries[0])
+	}
+	if summaries[0].Max != 5 {
+		t.Errorf("expected max 5, got %v", summaries[0].Max)
+	}
+
+	csvFile, err := os.Open(filepath.Join(deployDir, "metrics.csv"))
+	if err != nil {
+		t.Fatalf("expected metrics.csv to be written: %v", err)
+	}
+	defer csvFile.Close()
+
+	rows, err := csv.NewReader(csvFile).ReadAll()
+	if err != nil {
+		t.Fatalf("failed to parse metrics.csv: %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("expected header + 1 data row, got %d rows", len(rows))
+	}
+	wantHeader := []string{"metric", "locale", "stage", "min", "max", "avg", "last", "wow_delta"}
+	for i, col := range wantHeader {
+		if rows[0][i] != col {
+			t.Errorf("expected header column %d to be %q, got %q", i, col, rows[0][i])
+		}
+	}
+	if rows[1][0] != "totalEnablements" || rows[1][3] != "1" || rows[1][4] != "5" {
+		t.Errorf("unexpected data row: %v", rows[1])
+	}
+}
+
+func TestGenerateDashboardWithoutChart(t *testing.T) {
+	deployDir := t.TempDir()
+
+	if err := GenerateDashboard(deployDir, testMetricResponses(), nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	jsonBytes, err := os.ReadFile(filepath.Join(deployDir, "metrics.json"))
+	if err != nil {
+		t.Fatalf("expected metrics.json to be written: %v", err)
+	}
+	var summaries []Summary
+	if err := json.Unmarshal(jsonBytes, &summaries); err != nil {
+		t.Fatalf("failed to parse metrics.json: %v", err)
+	}
+	if summaries[0].ChartFile != "" {
+		t.Errorf("expected no chart file when none provided, got %q", summaries[0].ChartFile)
+	}
+}
@@ -0,0 +1,58 @@
+package report
+
+import "github.com/ChargePoint/bitrise-step-alexa-metrics/internal/alexa"
+
+// Summary holds the aggregate stats shown for a single metric in the
+// dashboard table.
+type Summary struct {
+	Metric    string  `json:"metric"`
+	Locale    string  `json:"locale,omitempty"`
+	Stage     string  `json:"stage,omitempty"`
+	Min       float64 `json:"min"`
+	Max       float64 `json:"max"`
+	Avg       float64 `json:"avg"`
+	Last      float64 `json:"last"`
+	WoWDelta  float64 `json:"wowDelta"`
+	ChartFile string  `json:"chartFile,omitempty"`
+}
+
+// ComputeSummary reduces a metric's time series down to the min/max/avg/last
+// values plus the week-over-week delta (the change between the last value
+// and the value 7 samples earlier, or the first value if the series is
+// shorter than that).
+func ComputeSummary(metricResponse alexa.MetricsResponse) Summary {
+	summary := Summary{
+		Metric: metricResponse.Metric,
+		Locale: metricResponse.Locale,
+		Stage:  metricResponse.Stage,
+	}
+
+	values := metricResponse.Values
+	if len(values) == 0 {
+		return summary
+	}
+
+	summary.Min = values[0]
+	summary.Max = values[0]
+	var sum float64
+	for _, v := range values {
+		if v < summary.Min {
+			summary.Min = v
+		}
+		if v > summary.Max {
+			summary.Max = v
+		}
+		sum += v
+	}
+	summary.Avg = sum / float64(len(values))
+	summary.Last = values[len(values)-1]
+
+	const weekOverWeekLag = 7
+	priorIndex := len(values) - 1 - weekOverWeekLag
+	if priorIndex < 0 {
+		priorIndex = 0
+	}
+	summary.WoWDelta = summary.Last - values[priorIndex]
+
+	return summary
+}
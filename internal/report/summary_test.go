@@ -0,0 +1,36 @@
+package report
+
+import (
+	"testing"
+
+	"github.com/ChargePoint/bitrise-step-alexa-metrics/internal/alexa"
+)
+
+func TestComputeSummary(t *testing.T) {
+	metricResponse := alexa.MetricsResponse{
+		Metric: "totalEnablements",
+		Values: []float64{1, 5, 3, 2, 4, 6, 8},
+	}
+
+	summary := ComputeSummary(metricResponse)
+
+	if summary.Min != 1 {
+		t.Errorf("expected min 1, got %v", summary.Min)
+	}
+	if summary.Max != 8 {
+		t.Errorf("expected max 8, got %v", summary.Max)
+	}
+	if summary.Last != 8 {
+		t.Errorf("expected last 8, got %v", summary.Last)
+	}
+	if summary.WoWDelta != 7 {
+		t.Errorf("expected wow delta 7 (last - first), got %v", summary.WoWDelta)
+	}
+}
+
+func TestComputeSummaryEmpty(t *testing.T) {
+	summary := ComputeSummary(alexa.MetricsResponse{Metric: "empty"})
+	if summary.Metric != "empty" {
+		t.Errorf("expected metric name to be preserved, got %v", summary.Metric)
+	}
+}
@@ -1,24 +1,23 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
+	"flag"
 	"fmt"
-	"io/ioutil"
-	"log"
-	"net/http"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
-	chart "github.com/wcharczuk/go-chart/v2"
+	"github.com/ChargePoint/bitrise-step-alexa-metrics/internal/alexa"
+	"github.com/ChargePoint/bitrise-step-alexa-metrics/internal/analyze"
+	"github.com/ChargePoint/bitrise-step-alexa-metrics/internal/exporter"
+	"github.com/ChargePoint/bitrise-step-alexa-metrics/internal/report"
 )
 
-var httpClient = &http.Client{Timeout: 200 * time.Second}
-
-var metrics = [...]string{
+var metricNames = [...]string{
 	"uniqueCustomers",
 	"totalEnablements",
-	"totalEnablements",
 	"successfulUtterances",
 	"failedUtterances",
 	"totalSessions",
@@ -27,14 +26,6 @@ var metrics = [...]string{
 	"userEndedSessions",
 	"skillEndedSessions"}
 
-func lwaOAuthURL() string {
-	return "https://api.amazon.com/auth/o2/token"
-}
-
-func metricsSMAPIURL(skillID string, startTime string, endTime string, metric string) string {
-	return "https://api.amazonalexa.com/v1/skills/" + skillID + "/metrics?startTime=" + startTime + "&endTime=" + endTime + "&period=P1D&metric=" + metric + "&stage=live&skillType=custom&locale=en-US"
-}
-
 func getenv(key, fallback string) string {
 	value := os.Getenv(key)
 	if len(value) == 0 {
@@ -43,162 +34,248 @@ func getenv(key, fallback string) string {
 	return value
 }
 
-type AuthenticateResponse struct {
-	Access_token  string `json:"access_token"`
-	Expires_in    int    `json:"expires_in"`
-	Token_type    string `json:"token_type"`
-	Refresh_token string `json:"refresh_token"`
-}
-
-type MetricsResponse struct {
-	Metric     string    `json:"metric"`
-	Timestamps []string  `json:"timestamps"`
-	Values     []float64 `json:"values"`
+func splitCSV(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
 }
 
-func getLWAAccessToken(clientID string, clientSecret string, refreshToken string, target interface{}) error {
-	var bodyString = "grant_type=refresh_token"
-	bodyString += "&client_id=" + clientID
-	bodyString += "&client_secret=" + clientSecret
-	bodyString += "&refresh_token=" + refreshToken
+func main() {
+	lwaClientID := flag.String("lwa_client_id", getenv("lwa_client_id", ""), "LWA client ID")
+	lwaClientSecret := flag.String("lwa_client_secret", getenv("lwa_client_secret", ""), "LWA client secret")
+	lwaRefreshToken := flag.String("lwa_refresh_token", getenv("lwa_refresh_token", ""), "LWA refresh token")
+	skillID := flag.String("custom_skill_id", getenv("custom_skill_id", ""), "Alexa skill ID")
+	deployDir := flag.String("deploy_dir", getenv("BITRISE_DEPLOY_DIR", ""), "Directory to write charts and reports to")
+
+	startTimeFlag := flag.String("start_time", getenv("start_time", ""), "RFC3339 start of the metrics window (overrides lookback_days)")
+	endTimeFlag := flag.String("end_time", getenv("end_time", ""), "RFC3339 end of the metrics window (defaults to now)")
+	lookbackDays := flag.Int("lookback_days", atoiOr(getenv("lookback_days", "7"), 7), "Size of the metrics window in days, used when start_time is not set")
+	period := flag.String("period", getenv("period", "P1D"), "SMAPI granularity: P1D, PT1H, or PT15M")
+	locales := flag.String("locales", getenv("locales", "en-US"), "Comma-separated list of locales to fetch")
+	stages := flag.String("stages", getenv("stages", "live"), "Comma-separated list of stages to fetch: live, development")
+	skillType := flag.String("skill_type", getenv("skill_type", "custom"), "SMAPI skillType: custom or smartHome")
+	maxConcurrentRequests := flag.Int("max_concurrent_requests", atoiOr(getenv("max_concurrent_requests", "4"), 4), "Maximum number of concurrent SMAPI requests")
+
+	pushgatewayURL := flag.String("pushgateway_url", getenv("pushgateway_url", ""), "Prometheus Pushgateway base URL; if set, metrics are also pushed here")
+	pushgatewayJob := flag.String("pushgateway_job", getenv("pushgateway_job", "alexa-metrics"), "Pushgateway job name")
+	pushgatewayBasicAuthUser := flag.String("pushgateway_basic_auth_user", getenv("pushgateway_basic_auth_user", ""), "Pushgateway basic auth username")
+	pushgatewayBasicAuthPass := flag.String("pushgateway_basic_auth_pass", getenv("pushgateway_basic_auth_pass", ""), "Pushgateway basic auth password")
+	pushgatewayBearerToken := flag.String("pushgateway_bearer_token", getenv("pushgateway_bearer_token", ""), "Pushgateway bearer token, used instead of basic auth when set")
+
+	failIfFailedUtterancesGT := flag.String("fail_if_failed_utterances_gt", getenv("fail_if_failed_utterances_gt", ""), "Fail the step if total failedUtterances over the window exceeds this value")
+	failIfSuccessRateLT := flag.String("fail_if_success_rate_lt", getenv("fail_if_success_rate_lt", ""), "Fail the step if successfulSessions/totalSessions over the window falls below this value")
+	anomalyMADK := flag.Float64("anomaly_mad_k", atofOr(getenv("anomaly_mad_k", "3.5"), 3.5), "Number of median absolute deviations a point must deviate from the trailing median to be flagged")
+	failOnAnomaly := flag.Bool("fail_on_anomaly", getenv("fail_on_anomaly", "false") == "true", "Exit non-zero when a critical anomaly is found")
+
+	flag.Parse()
+
+	if *lwaClientID == "" {
+		fmt.Println("LWA Client ID is required")
+		os.Exit(1)
+	}
 
-	body := strings.NewReader(bodyString)
-	req, err := http.NewRequest("POST", lwaOAuthURL(), body)
-	if err != nil {
-		log.Fatal(err)
+	if *lwaClientSecret == "" {
+		fmt.Println("LWA Client secret is required")
 		os.Exit(1)
 	}
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
-	resp, err := httpClient.Do(req)
-	if err != nil {
-		log.Fatal(err)
+	if *lwaRefreshToken == "" {
+		fmt.Println("LWA refresh token is required")
 		os.Exit(1)
 	}
-	defer resp.Body.Close()
 
-	return json.NewDecoder(resp.Body).Decode(target)
-}
+	if *skillID == "" {
+		fmt.Println("Skill ID is required")
+		os.Exit(1)
+	}
 
-func formatTimeDate(t time.Time) string {
-	return fmt.Sprintf("%d-%02d-%02dT%02d:%02d:%02dZ", t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second())
-}
+	if *deployDir == "" {
+		fmt.Println("Deploy directory not found")
+		os.Exit(1)
+	}
 
-func getSkillMetric(skillID string, metric string, accessToken string, target interface{}) error {
 	endTime := time.Now()
-	startTime := endTime.AddDate(0, 0, -7)
-
-	url := metricsSMAPIURL(skillID, formatTimeDate(startTime), formatTimeDate(endTime), metric)
+	if *endTimeFlag != "" {
+		parsed, err := time.Parse(time.RFC3339, *endTimeFlag)
+		if err != nil {
+			fmt.Println("Invalid end_time:", err)
+			os.Exit(1)
+		}
+		endTime = parsed
+	}
 
-	var bodyString = ""
-	body := strings.NewReader(bodyString)
+	startTime := endTime.AddDate(0, 0, -*lookbackDays)
+	if *startTimeFlag != "" {
+		parsed, err := time.Parse(time.RFC3339, *startTimeFlag)
+		if err != nil {
+			fmt.Println("Invalid start_time:", err)
+			os.Exit(1)
+		}
+		startTime = parsed
+	}
 
-	req, err := http.NewRequest("GET", url, body)
+	fmt.Println("Get the LWA access token")
 
-	authorization_value := "Bearer " + accessToken
-	req.Header.Set("Authorization", authorization_value)
+	client := alexa.NewClient(*lwaClientID, *lwaClientSecret, *lwaRefreshToken)
 
-	resp, err := httpClient.Do(req)
+	auth, err := client.GetAccessToken()
 	if err != nil {
-		log.Fatal(err)
+		fmt.Println("Failed to get LWA access token:", err)
 		os.Exit(1)
 	}
 
-	defer resp.Body.Close()
-
-	responseData, sErr := ioutil.ReadAll(resp.Body)
-	if sErr != nil {
-		log.Fatal(err)
-		os.Exit(1)
+	fmt.Println("LWA Access Token", auth.AccessToken)
+
+	var requests []alexa.MetricRequest
+	for _, metricName := range metricNames {
+		for _, locale := range splitCSV(*locales) {
+			for _, stage := range splitCSV(*stages) {
+				requests = append(requests, alexa.MetricRequest{
+					SkillID:   *skillID,
+					Metric:    metricName,
+					Locale:    locale,
+					Stage:     stage,
+					SkillType: *skillType,
+					Period:    *period,
+					StartTime: startTime,
+					EndTime:   endTime,
+				})
+			}
+		}
 	}
 
-	return json.Unmarshal(responseData, &target)
-}
+	results, errs := client.FetchMetrics(context.Background(), requests, *maxConcurrentRequests)
 
-func generateMetricChart(deployDir string, metricResponse MetricsResponse) {
-	series := make([]chart.Series, 1)
-	count := len(metricResponse.Values)
-	xValues := make([]time.Time, count)
-	for i := 0; i < count; i++ {
-		xValues[i], _ = time.Parse(time.RFC3339, metricResponse.Timestamps[i])
-	}
-
-	series[0] = chart.TimeSeries{
-		Name:    metricResponse.Metric,
-		XValues: xValues,
-		YValues: metricResponse.Values,
-	}
-
-	graph := chart.Chart{
-		Background: chart.Style{
-			Padding: chart.NewBox(20, 20, 20, 20),
-		},
-		XAxis: chart.XAxis{
-			Name: "Time",
-		},
-		YAxis: chart.YAxis{
-			Name: "Value",
-		},
-		Series: series,
-	}
-	filepath := deployDir + "/" + metricResponse.Metric + ".png"
-	f, _ := os.Create(filepath)
-	defer f.Close()
-	graph.Render(chart.PNG, f)
-	fmt.Println(filepath)
-}
+	var failures int
+	seriesByMetric := make(map[string][]alexa.MetricsResponse)
+	for _, metricName := range metricNames {
+		seriesByMetric[metricName] = nil
+	}
 
-func main() {
-	fmt.Println("Get the LWA access token")
+	var metricResponses []alexa.MetricsResponse
+	for i, metricResponse := range results {
+		if err := errs[i]; err != nil {
+			req := requests[i]
+			fmt.Printf("Failed to fetch %s (%s/%s): %v\n", req.Metric, req.Locale, req.Stage, err)
+			failures++
+			continue
+		}
 
-	lwaClientID := getenv("lwa_client_id", "")
-	lwaClientSecret := getenv("lwa_client_secret", "")
-	lwaRefreshToken := getenv("lwa_refresh_token", "")
-	skillID := getenv("custom_skill_id", "")
-	deployDir := getenv("BITRISE_DEPLOY_DIR", "")
+		fmt.Println("Number of " + metricResponse.Metric + " (" + metricResponse.Locale + "/" + metricResponse.Stage + ") on each day last week")
+		for i := 0; i < len(metricResponse.Values); i++ {
+			fmt.Println(metricResponse.Timestamps[i], metricResponse.Values[i])
+		}
+		seriesByMetric[metricResponse.Metric] = append(seriesByMetric[metricResponse.Metric], metricResponse)
+		metricResponses = append(metricResponses, metricResponse)
+	}
 
-	if lwaClientID == "" {
-		fmt.Println("LWA Client ID is required")
-		os.Exit(1)
+	var thresholds analyze.Thresholds
+	if *failIfFailedUtterancesGT != "" {
+		v, err := strconv.ParseFloat(*failIfFailedUtterancesGT, 64)
+		if err != nil {
+			fmt.Println("Invalid fail_if_failed_utterances_gt:", err)
+			os.Exit(1)
+		}
+		thresholds.FailIfFailedUtterancesGT = &v
+	}
+	if *failIfSuccessRateLT != "" {
+		v, err := strconv.ParseFloat(*failIfSuccessRateLT, 64)
+		if err != nil {
+			fmt.Println("Invalid fail_if_success_rate_lt:", err)
+			os.Exit(1)
+		}
+		thresholds.FailIfSuccessRateLT = &v
 	}
 
-	if lwaClientSecret == "" {
-		fmt.Println("LWA Client secret is required")
+	anomalies := analyze.Analyze(metricResponses, thresholds, *anomalyMADK)
+	if err := analyze.WriteArtifact(*deployDir, anomalies); err != nil {
+		fmt.Println("Failed to write anomalies.json:", err)
 		os.Exit(1)
 	}
+	for _, a := range anomalies {
+		fmt.Printf("Anomaly [%s] %s (%s/%s) at %s: value=%v expected=[%s,%s]: %s\n",
+			a.Severity, a.Metric, a.Locale, a.Stage, a.Timestamp, a.Value, formatBound(a.ExpectedLow), formatBound(a.ExpectedHigh), a.Reason)
+	}
 
-	if lwaRefreshToken == "" {
-		fmt.Println("LWA refresh token is required")
-		os.Exit(1)
+	chartFiles := make(map[string]string, len(metricNames))
+	for _, metricName := range metricNames {
+		series := seriesByMetric[metricName]
+		if len(series) == 0 {
+			continue
+		}
+
+		chartFile, err := report.GenerateMetricChart(*deployDir, metricName, series, anomalies)
+		if err != nil {
+			fmt.Println("Failed to render chart for", metricName, ":", err)
+			os.Exit(1)
+		}
+		fmt.Println(chartFile)
+		chartFiles[metricName] = chartFile
 	}
 
-	if skillID == "" {
-		fmt.Println("Skill ID is required")
+	if err := report.GenerateDashboard(*deployDir, metricResponses, chartFiles); err != nil {
+		fmt.Println("Failed to generate dashboard:", err)
 		os.Exit(1)
 	}
 
-	if deployDir == "" {
-		fmt.Println("Deploy directory not found")
+	if *pushgatewayURL != "" {
+		if err := pushMetrics(*pushgatewayURL, *pushgatewayJob, *pushgatewayBasicAuthUser, *pushgatewayBasicAuthPass, *pushgatewayBearerToken, *skillID, metricResponses); err != nil {
+			fmt.Println("Failed to push metrics to Pushgateway:", err)
+			os.Exit(1)
+		}
+	}
+
+	if failures > 0 {
+		fmt.Printf("%d of %d metric requests failed\n", failures, len(requests))
 		os.Exit(1)
 	}
 
-	auth := AuthenticateResponse{}
-	getLWAAccessToken(lwaClientID, lwaClientSecret, lwaRefreshToken, &auth)
+	if *failOnAnomaly && analyze.HasCritical(anomalies) {
+		fmt.Println("Critical anomaly detected, failing the build")
+		os.Exit(1)
+	}
 
-	fmt.Println("LWA Access Token", auth.Access_token)
+	os.Exit(0)
+}
 
-	for i := 0; i < 1; i++ {
-		metricResponse := MetricsResponse{}
-		getSkillMetric(skillID, metrics[i], auth.Access_token, &metricResponse)
+func atoiOr(value string, fallback int) int {
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return fallback
+	}
+	return parsed
+}
 
-		fmt.Println("Number of " + metricResponse.Metric + " on each day last week")
-		for i := 0; i < len(metricResponse.Values); i++ {
-			fmt.Println(metricResponse.Timestamps[i], metricResponse.Values[i])
-		}
+func atofOr(value string, fallback float64) float64 {
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return fallback
+	}
+	return parsed
+}
 
-		generateMetricChart(deployDir, metricResponse)
+func formatBound(bound *float64) string {
+	if bound == nil {
+		return "-"
+	}
+	return strconv.FormatFloat(*bound, 'g', -1, 64)
+}
 
+func pushMetrics(url, job, basicAuthUser, basicAuthPass, bearerToken, skillID string, metricResponses []alexa.MetricsResponse) error {
+	samples, err := exporter.SamplesFromMetricsResponses(skillID, metricResponses)
+	if err != nil {
+		return err
 	}
 
-	os.Exit(0)
+	sink := exporter.NewPushgatewaySink(url, job)
+	sink.BasicAuthUser = basicAuthUser
+	sink.BasicAuthPass = basicAuthPass
+	sink.BearerToken = bearerToken
+
+	return sink.Push(samples)
 }